@@ -0,0 +1,47 @@
+package dpdk
+
+import (
+	"os"
+	"strings"
+)
+
+// Config holds the knobs needed to run the DPDK suite against clusters where the SR-IOV
+// resource pools, NADs or pod sizing differ from the defaults below.
+type Config struct {
+	DpdkResourceName   string
+	NetdevResourceName string
+	DpdkNetwork        string
+	NetdevNetwork      string
+	HugepageSize       string
+	HugepageAmount     string
+	CPUCount           string
+}
+
+// NewConfig builds a Config from the suite's environment variables, falling back to the
+// values the suite has always used when unset.
+func NewConfig() Config {
+	return Config{
+		DpdkResourceName:   getEnv("DPDK_RESOURCE_NAME", "openshift.io/dpdknic"),
+		NetdevResourceName: getEnv("NETDEV_RESOURCE_NAME", "openshift.io/netdevnic"),
+		DpdkNetwork:        getEnv("DPDK_NETWORK", dpdkAnnotationNetwork),
+		NetdevNetwork:      getEnv("NETDEV_NETWORK", netdevAnnotationNetwork),
+		HugepageSize:       getEnv("HUGEPAGE_SIZE", "1Gi"),
+		HugepageAmount:     getEnv("HUGEPAGE_AMOUNT", "4Gi"),
+		CPUCount:           getEnv("CPU_COUNT", "4"),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// pciDeviceEnvVar mirrors the SR-IOV device plugin's env var naming convention: the
+// resource name is uppercased and '/', '.', '-' are replaced with '_', so that
+// "openshift.io/intelnics" becomes "PCIDEVICE_OPENSHIFT_IO_INTELNICS".
+func pciDeviceEnvVar(resourceName string) string {
+	replacer := strings.NewReplacer("/", "_", ".", "_", "-", "_")
+	return "PCIDEVICE_" + replacer.Replace(strings.ToUpper(resourceName))
+}
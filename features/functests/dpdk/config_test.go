@@ -0,0 +1,24 @@
+package dpdk
+
+import "testing"
+
+func TestPciDeviceEnvVar(t *testing.T) {
+	cases := []struct {
+		name         string
+		resourceName string
+		want         string
+	}{
+		{"default dpdk resource", "openshift.io/dpdknic", "PCIDEVICE_OPENSHIFT_IO_DPDKNIC"},
+		{"intel nics", "openshift.io/intelnics", "PCIDEVICE_OPENSHIFT_IO_INTELNICS"},
+		{"mellanox nics", "openshift.io/mlxnics", "PCIDEVICE_OPENSHIFT_IO_MLXNICS"},
+		{"resource with dots and dashes", "openshift.io/my-nic.pool", "PCIDEVICE_OPENSHIFT_IO_MY_NIC_POOL"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pciDeviceEnvVar(tc.resourceName); got != tc.want {
+				t.Errorf("pciDeviceEnvVar(%q) = %q, want %q", tc.resourceName, got, tc.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package dpdk
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -14,21 +14,44 @@ import (
 	k8sv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilexec "k8s.io/client-go/util/exec"
 
-	"github.com/openshift-kni/baremetal-deploy/features/functests/utils/namespace"
 	"github.com/openshift-kni/baremetal-deploy/features/functests/utils/clients"
+	"github.com/openshift-kni/baremetal-deploy/features/functests/utils/namespace"
+	"github.com/openshift-kni/baremetal-deploy/features/functests/utils/pods"
+)
+
+const (
+	dpdkHostLabel           = "feature.node.kubernetes.io/network-sriov.capable=true"
+	hostnameLabel           = "kubernetes.io/hostname"
+	dpdkAnnotationNetwork   = "dpdk-network"
+	netdevAnnotationNetwork = "netdev-network"
+	testDpdkNamespace       = "dpdk-testing"
+	testCmdPath             = "/opt/test.sh"
+	testContainerName       = "test-dpdk"
+
+	// partitionHugepagesRequired is the amount of allocatable hugepage memory, at whatever
+	// page size testConfig.HugepageSize configures, a node needs to be picked for the
+	// partitioning test, which runs one DPDK pod and one kernel netdevice pod on it.
+	partitionHugepagesRequired = "5Gi"
+
+	// timeoutExitCode is the exit status bash reports when `timeout` kills the wrapped
+	// testpmd command after the test duration elapses, i.e. a successful run.
+	timeoutExitCode = 124
 )
 
+// podVariant selects which flavor of test pod createTestPod should build.
+type podVariant string
+
 const (
-	dpdkHostLabel         = "feature.node.kubernetes.io/network-sriov.capable=true"
-	hostnameLabel         = "kubernetes.io/hostname"
-	dpdkAnnotationNetwork = "dpdk-network"
-	testDpdkNamespace     = "dpdk-testing"
-	testCmdPath           = "/opt/test.sh"
+	variantDPDK      podVariant = "dpdk"
+	variantNetdevice podVariant = "netdevice"
+	variantSmoke     podVariant = "smoke"
 )
 
 var dpdkAppImage string
 var c *k8sv1.ConfigMap
+var testConfig Config
 
 func init() {
 	// Set DPDK app image
@@ -37,6 +60,8 @@ func init() {
 		dpdkAppImage = "docker.io/dorzheh/dpdk-centos7:latest"
 		//"quay.io/schseba/dpdk-prod:test"
 	}
+
+	testConfig = NewConfig()
 }
 
 var _ = Describe("dpdk", func() {
@@ -47,110 +72,396 @@ var _ = Describe("dpdk", func() {
 
 		It("Should forward and receive packets", func() {
 			nodes := getListOfNodes(dpdkHostLabel)
-			c = createTestpmdConfigMap(testDpdkNamespace)
+			c = createTestpmdConfigMap(testDpdkNamespace, "testpmd", "mac")
 			for _, n := range nodes {
-				p := createTestPod(n.Name, testDpdkNamespace, c.Name)
+				p := createDpdkTestPod(n.Name, testDpdkNamespace, c.Name, dpdkAnnotationNetwork)
 				waitForReadiness(p.Namespace, p.Name)
-				By(fmt.Sprintf("Execute %s inside the pod %s", testCmdPath, p.Name))
-				out, err := exec.Command("oc", "rsh", "-n", p.Namespace, p.Name, "bash", "-c", testCmdPath).CombinedOutput()
-				Expect(err).ToNot(HaveOccurred(), fmt.Sprintf("cannot execute %s inside the pod %s", testCmdPath, p.Name) )
-				checkRxTx(string(out))
+				out := runTestCmd(p)
+				checkRxTx(out, 1)
 				deleteTestPod(p.Name)
 			}
 			deleteTestpmdConfigMap(c.Name)
 		})
 	})
+
+	var _ = Context("Partition SR-IOV VFs between a DPDK pod and a kernel netdevice pod", func() {
+		beforeAll(func() {
+			namespace.Create(testDpdkNamespace, clients.K8s)
+		})
+
+		It("Should forward DPDK traffic while a kernel netdevice pod on the same node drives it", func() {
+			dpdkNetwork, netdevNetwork := resolveNADs()
+
+			nodes := getListOfNodes(dpdkHostLabel)
+			n, ok := nodeWithCapacityForPartitionTest(nodes)
+			if !ok {
+				Skip(fmt.Sprintf("no candidate node has %s of allocatable %s hugepages for the partitioning test", partitionHugepagesRequired, testConfig.HugepageSize))
+			}
+
+			c = createTestpmdConfigMap(testDpdkNamespace, "testpmd", "mac")
+			dpdkPod := createDpdkTestPod(n.Name, testDpdkNamespace, c.Name, dpdkNetwork)
+			netdevPod := createNetdevTestPod(n.Name, testDpdkNamespace, netdevNetwork)
+			waitForReadiness(dpdkPod.Namespace, dpdkPod.Name)
+			waitForReadiness(netdevPod.Namespace, netdevPod.Name)
+
+			var wg sync.WaitGroup
+			var out string
+			wg.Add(1)
+			By(fmt.Sprintf("Execute %s inside the pod %s", testCmdPath, dpdkPod.Name))
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				out = runToCompletion(dpdkPod, fmt.Sprintf("timeout %ds %s", int(testDuration().Seconds()), testCmdPath))
+			}()
+
+			By(fmt.Sprintf("Generate traffic from the netdevice pod %s toward the DPDK pod", netdevPod.Name))
+			_, stderr, err := pods.ExecCommand(clients.K8s, netdevPod, testContainerName, []string{"bash", "-c", "ping -c 10 -I net1 255.255.255.255 || iperf -c 255.255.255.255 -t 5"})
+			Expect(err).ToNot(HaveOccurred(), fmt.Sprintf("cannot generate traffic from the pod %s: %s", netdevPod.Name, stderr))
+			wg.Wait()
+
+			checkRxTx(out, 1)
+			deleteTestPod(dpdkPod.Name)
+			deleteTestPod(netdevPod.Name)
+			deleteTestpmdConfigMap(c.Name)
+		})
+	})
+
+	var _ = Context("Run back-to-back DPDK traffic between a generator and a forwarder pod", func() {
+		beforeAll(func() {
+			namespace.Create(testDpdkNamespace, clients.K8s)
+		})
+
+		It("Should forward traffic driven by a dedicated packet generator pod", func() {
+			nodes := getListOfNodes(dpdkHostLabel)
+			if len(nodes) < 2 {
+				Skip("need at least two SR-IOV capable nodes to run the generator and the forwarder on separate workers")
+			}
+			forwarderNode, generatorNode := nodes[0], nodes[1]
+
+			forwarderCM := createTestpmdConfigMap(testDpdkNamespace, "testpmd-forward", "mac")
+			forwarderPod := createDpdkTestPod(forwarderNode.Name, testDpdkNamespace, forwarderCM.Name, dpdkAnnotationNetwork)
+			waitForReadiness(forwarderPod.Namespace, forwarderPod.Name)
+
+			By(fmt.Sprintf("Discover the forwarder's MAC address from pod %s", forwarderPod.Name))
+			forwarderMAC := execInPod(forwarderPod, "cat /sys/class/net/net1/address")
+
+			generatorCM := createTestpmdConfigMap(testDpdkNamespace, "testpmd-generate", "txonly", fmt.Sprintf("--eth-peer=0,%s", forwarderMAC))
+			generatorPod := createDpdkTestPod(generatorNode.Name, testDpdkNamespace, generatorCM.Name, dpdkAnnotationNetwork)
+			waitForReadiness(generatorPod.Namespace, generatorPod.Name)
+
+			duration := testDuration()
+			runCmd := fmt.Sprintf("timeout %ds %s", int(duration.Seconds()), testCmdPath)
+
+			var wg sync.WaitGroup
+			var forwarderOut string
+			wg.Add(2)
+			By(fmt.Sprintf("Start the generator pod %s", generatorPod.Name))
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				runToCompletion(generatorPod, runCmd)
+			}()
+			By(fmt.Sprintf("Start the forwarder pod %s", forwarderPod.Name))
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				forwarderOut = runToCompletion(forwarderPod, runCmd)
+			}()
+			wg.Wait()
+
+			minPackets := testMinPPS() * int64(duration.Seconds())
+			checkRxTx(forwarderOut, minPackets)
+
+			deleteTestPod(generatorPod.Name)
+			deleteTestPod(forwarderPod.Name)
+			deleteTestpmdConfigMap(generatorCM.Name)
+			deleteTestpmdConfigMap(forwarderCM.Name)
+		})
+	})
+
+	var _ = Context("Validate HugePages", func() {
+		beforeAll(func() {
+			namespace.Create(testDpdkNamespace, clients.K8s)
+		})
+
+		It("Should allocate the requested 1Gi hugepages", func() {
+			verifyHugepageAllocation("1Gi", "4Gi", "4")
+		})
+
+		It("Should allocate the requested 2Mi hugepages", func() {
+			verifyHugepageAllocation("2Mi", "256Mi", "2")
+		})
+	})
+
+	var _ = Context("Validate NUMA alignment", func() {
+		beforeAll(func() {
+			namespace.Create(testDpdkNamespace, clients.K8s)
+		})
+
+		It("Should schedule the pod's CPUs on the same NUMA node as its SR-IOV device", func() {
+			nodes := getListOfNodes(dpdkHostLabel)
+			n := nodes[0]
+
+			p := createSmokeTestPod(n.Name, testDpdkNamespace, dpdkAnnotationNetwork, "4", "1Gi", "4Gi")
+			waitForReadiness(p.Namespace, p.Name)
+
+			cpuNode := numaNodeOfCPUs(p, execCPUSet(p))
+			deviceNode := numaNodeOfPCIDevice(p, execPCIDevice(p))
+			Expect(deviceNode).To(Equal(cpuNode), "the pod's CPUs and its SR-IOV device are on different NUMA nodes")
+
+			deleteTestPod(p.Name)
+		})
+	})
 })
 
-// creteTestPod creates a pod that will act as a runtime for the DPDK test application
-func createTestPod(nodeName, namespace, configMapName string) *k8sv1.Pod {
+// resolveNADs returns the NetworkAttachmentDefinition names used by the DPDK pod and the
+// kernel netdevice pod, falling back to the suite's default NADs when unset.
+func resolveNADs() (dpdkNetwork, netdevNetwork string) {
+	return testConfig.DpdkNetwork, testConfig.NetdevNetwork
+}
+
+// testDuration returns how long the generator/forwarder pair should run for, as set by
+// DPDK_TEST_DURATION (seconds), defaulting to 30s.
+func testDuration() time.Duration {
+	v := os.Getenv("DPDK_TEST_DURATION")
+	if v == "" {
+		return 30 * time.Second
+	}
+	seconds, err := strconv.Atoi(v)
+	Expect(err).ToNot(HaveOccurred(), "DPDK_TEST_DURATION must be an integer number of seconds")
+	return time.Duration(seconds) * time.Second
+}
+
+// testMinPPS returns the minimum packets-per-second the forwarder is expected to sustain,
+// as set by DPDK_TEST_MIN_PPS, defaulting to 1000.
+func testMinPPS() int64 {
+	v := os.Getenv("DPDK_TEST_MIN_PPS")
+	if v == "" {
+		return 1000
+	}
+	pps, err := strconv.ParseInt(v, 10, 64)
+	Expect(err).ToNot(HaveOccurred(), "DPDK_TEST_MIN_PPS must be an integer")
+	return pps
+}
+
+// nodeHasCapacityForPartitionTest checks that a node's allocatable hugepages of
+// testConfig.HugepageSize are at least partitionHugepagesRequired. Free SR-IOV VFs are
+// not checked, and hugepages already requested by pods running on the node are not
+// subtracted.
+func nodeHasCapacityForPartitionTest(n k8sv1.Node) bool {
+	hugepageResource := k8sv1.ResourceHugePagesPrefix + k8sv1.ResourceName(testConfig.HugepageSize)
+	allocatable, ok := n.Status.Allocatable[hugepageResource]
+	if !ok {
+		return false
+	}
+	required := resource.MustParse(partitionHugepagesRequired)
+	return allocatable.Cmp(required) >= 0
+}
+
+// nodeWithCapacityForPartitionTest returns the first node among candidates that has
+// capacity for the partitioning test, and false if none of them qualify.
+func nodeWithCapacityForPartitionTest(candidates []k8sv1.Node) (k8sv1.Node, bool) {
+	for _, n := range candidates {
+		if nodeHasCapacityForPartitionTest(n) {
+			return n, true
+		}
+	}
+	return k8sv1.Node{}, false
+}
+
+// podConfig carries the parameters needed to build a DPDK, a kernel netdevice or a smoke
+// test pod; createTestPod fills in the variant-specific pieces from it.
+type podConfig struct {
+	variant        podVariant
+	nodeName       string
+	namespace      string
+	network        string
+	configMapName  string
+	cpuCount       string
+	hugepageSize   string
+	hugepageAmount string
+}
+
+// createTestPod creates a pod that will act as a runtime for the DPDK test application,
+// a plain kernel netdevice pod, or a hugepage smoke test pod, depending on cfg.variant.
+func createTestPod(cfg podConfig) *k8sv1.Pod {
 	defaultMode := int32(0755)
 
-	res := &k8sv1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: "test-dpdk",
-			Labels: map[string]string{
-				"app": "test-dpdk",
+	container := k8sv1.Container{
+		Name:            testContainerName,
+		Image:           dpdkAppImage,
+		Command:         []string{"/bin/bash", "-c", "--"},
+		Args:            []string{"while true; do sleep inf; done;"},
+		ImagePullPolicy: "Always",
+	}
+
+	var volumes []k8sv1.Volume
+
+	switch cfg.variant {
+	case variantDPDK:
+		dpdkHugepageResource := k8sv1.ResourceHugePagesPrefix + k8sv1.ResourceName(testConfig.HugepageSize)
+		container.SecurityContext = &k8sv1.SecurityContext{
+			Capabilities: &k8sv1.Capabilities{
+				Add: []k8sv1.Capability{"IPC_LOCK"},
 			},
-			Annotations: map[string]string{
-				"k8s.v1.cni.cncf.io/networks": dpdkAnnotationNetwork,
+		}
+		container.Resources = k8sv1.ResourceRequirements{
+			Limits: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse(testConfig.CPUCount),
+				k8sv1.ResourceMemory: resource.MustParse("1000Mi"),
+				dpdkHugepageResource: resource.MustParse(testConfig.HugepageAmount),
 			},
-			Namespace: namespace,
-		},
-		Spec: k8sv1.PodSpec{
-			RestartPolicy: k8sv1.RestartPolicyNever,
-			Containers: []k8sv1.Container{
-				{
-					Name:  "test-dpdk",
-					Image: dpdkAppImage,
-					SecurityContext: &k8sv1.SecurityContext{
-						Capabilities: &k8sv1.Capabilities{
-							Add: []k8sv1.Capability{"IPC_LOCK"},
-						},
-					},
-					Command:         []string{"/bin/bash", "-c", "--"},
-					Args:            []string{"while true; do sleep inf; done;"},
-					ImagePullPolicy: "Always",
-					Resources: k8sv1.ResourceRequirements{
-						Limits: k8sv1.ResourceList{
-							k8sv1.ResourceCPU:                     resource.MustParse("4"),
-							k8sv1.ResourceMemory:                  resource.MustParse("1000Mi"),
-							k8sv1.ResourceHugePagesPrefix + "1Gi": resource.MustParse("4Gi"),
-						},
-						Requests: k8sv1.ResourceList{
-							k8sv1.ResourceCPU:                     resource.MustParse("4"),
-							k8sv1.ResourceMemory:                  resource.MustParse("1000Mi"),
-							k8sv1.ResourceHugePagesPrefix + "1Gi": resource.MustParse("4Gi"),
-						},
-					},
-					VolumeMounts: []k8sv1.VolumeMount{
-						{
-							Name:      "hugepage",
-							MountPath: "/mnt/huge",
-							ReadOnly:  false,
-						},
-						{
-							Name:      "testcmd",
-							MountPath: testCmdPath,
-							SubPath:   "test.sh",
-						},
+			Requests: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse(testConfig.CPUCount),
+				k8sv1.ResourceMemory: resource.MustParse("1000Mi"),
+				dpdkHugepageResource: resource.MustParse(testConfig.HugepageAmount),
+			},
+		}
+		container.VolumeMounts = []k8sv1.VolumeMount{
+			{
+				Name:      "hugepage",
+				MountPath: "/mnt/huge",
+				ReadOnly:  false,
+			},
+			{
+				Name:      "testcmd",
+				MountPath: testCmdPath,
+				SubPath:   "test.sh",
+			},
+		}
+		volumes = []k8sv1.Volume{
+			{
+				Name: "hugepage",
+				VolumeSource: k8sv1.VolumeSource{
+					EmptyDir: &k8sv1.EmptyDirVolumeSource{
+						Medium: k8sv1.StorageMediumHugePages,
 					},
 				},
 			},
-			Volumes: []k8sv1.Volume{
-				{
-					Name: "hugepage",
-					VolumeSource: k8sv1.VolumeSource{
-						EmptyDir: &k8sv1.EmptyDirVolumeSource{
-							Medium: k8sv1.StorageMediumHugePages,
-						},
+			{
+				Name: "testcmd",
+				VolumeSource: k8sv1.VolumeSource{
+					ConfigMap: &k8sv1.ConfigMapVolumeSource{
+						k8sv1.LocalObjectReference{Name: cfg.configMapName},
+						nil,
+						&defaultMode,
+						nil,
 					},
 				},
-				{
-					Name: "testcmd",
-					VolumeSource: k8sv1.VolumeSource{
-						ConfigMap: &k8sv1.ConfigMapVolumeSource{
-							k8sv1.LocalObjectReference{Name: configMapName},
-							nil,
-							&defaultMode,
-							nil,
-						},
+			},
+		}
+	case variantNetdevice:
+		netdevResource := k8sv1.ResourceName(testConfig.NetdevResourceName)
+		container.Resources = k8sv1.ResourceRequirements{
+			Limits: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse("1"),
+				k8sv1.ResourceMemory: resource.MustParse("200Mi"),
+				netdevResource:       resource.MustParse("1"),
+			},
+			Requests: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse("1"),
+				k8sv1.ResourceMemory: resource.MustParse("200Mi"),
+				netdevResource:       resource.MustParse("1"),
+			},
+		}
+	case variantSmoke:
+		hugepageResource := k8sv1.ResourceHugePagesPrefix + k8sv1.ResourceName(cfg.hugepageSize)
+		container.Resources = k8sv1.ResourceRequirements{
+			Limits: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse(cfg.cpuCount),
+				k8sv1.ResourceMemory: resource.MustParse("500Mi"),
+				hugepageResource:     resource.MustParse(cfg.hugepageAmount),
+			},
+			Requests: k8sv1.ResourceList{
+				k8sv1.ResourceCPU:    resource.MustParse(cfg.cpuCount),
+				k8sv1.ResourceMemory: resource.MustParse("500Mi"),
+				hugepageResource:     resource.MustParse(cfg.hugepageAmount),
+			},
+		}
+		container.VolumeMounts = []k8sv1.VolumeMount{
+			{
+				Name:      "hugepage",
+				MountPath: "/mnt/huge",
+				ReadOnly:  false,
+			},
+		}
+		volumes = []k8sv1.Volume{
+			{
+				Name: "hugepage",
+				VolumeSource: k8sv1.VolumeSource{
+					EmptyDir: &k8sv1.EmptyDirVolumeSource{
+						Medium: k8sv1.StorageMediumHugePages,
 					},
 				},
 			},
+		}
+	}
 
+	res := &k8sv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "test-" + string(cfg.variant),
+			Labels: map[string]string{
+				"app": "test-" + string(cfg.variant),
+			},
+			Annotations: map[string]string{
+				"k8s.v1.cni.cncf.io/networks": cfg.network,
+			},
+			Namespace: cfg.namespace,
+		},
+		Spec: k8sv1.PodSpec{
+			RestartPolicy: k8sv1.RestartPolicyNever,
+			Containers:    []k8sv1.Container{container},
+			Volumes:       volumes,
 			NodeSelector: map[string]string{
-				hostnameLabel: nodeName,
+				hostnameLabel: cfg.nodeName,
 			},
 		},
 	}
 
 	By("Create a test pod")
-	p, err := clients.K8s.CoreV1().Pods(namespace).Create(res)
+	p, err := clients.K8s.CoreV1().Pods(cfg.namespace).Create(res)
 	Expect(err).ToNot(HaveOccurred(), "cannot create the test pod")
 	return p
 }
 
+// createDpdkTestPod builds a pod with a VF bound to vfio-pci, running the testpmd
+// wrapper script mounted from configMapName.
+func createDpdkTestPod(nodeName, namespace, configMapName, network string) *k8sv1.Pod {
+	return createTestPod(podConfig{
+		variant:       variantDPDK,
+		nodeName:      nodeName,
+		namespace:     namespace,
+		network:       network,
+		configMapName: configMapName,
+	})
+}
+
+// createNetdevTestPod builds a pod whose VF is surfaced as a kernel netdevice instead of
+// being bound to vfio-pci.
+func createNetdevTestPod(nodeName, namespace, network string) *k8sv1.Pod {
+	return createTestPod(podConfig{
+		variant:   variantNetdevice,
+		nodeName:  nodeName,
+		namespace: namespace,
+		network:   network,
+	})
+}
+
+// createSmokeTestPod builds a pod attached to the SR-IOV network with a parameterizable
+// hugepage request and no testpmd wrapper script, used as a fast smoke test for
+// Performance Addon / SR-IOV operator misconfigurations.
+func createSmokeTestPod(nodeName, namespace, network, cpuCount, hugepageSize, hugepageAmount string) *k8sv1.Pod {
+	return createTestPod(podConfig{
+		variant:        variantSmoke,
+		nodeName:       nodeName,
+		namespace:      namespace,
+		network:        network,
+		cpuCount:       cpuCount,
+		hugepageSize:   hugepageSize,
+		hugepageAmount: hugepageAmount,
+	})
+}
+
 // getListOfNodes finds appropriate nodes
 func getListOfNodes(nodeLabel string) []k8sv1.Node {
 	By("Getting list of nodes")
@@ -163,25 +474,35 @@ func getListOfNodes(nodeLabel string) []k8sv1.Node {
 }
 
 
-// createTestpmdConfigMap creates a ConfigMap that mounts testpmd wrapper script
-func createTestpmdConfigMap(namespace string) *k8sv1.ConfigMap {
+// createTestpmdConfigMap creates a ConfigMap that mounts a testpmd wrapper script under
+// configMapName, running in mode (e.g. "mac" for a forwarder, "txonly" for a packet
+// generator) with any extraArgs appended verbatim to testpmd's own arguments, after the
+// EAL "--" separator.
+func createTestpmdConfigMap(namespace, configMapName, mode string, extraArgs ...string) *k8sv1.ConfigMap {
+	args := "--forward-mode=" + mode
+	if len(extraArgs) > 0 {
+		args += " " + strings.Join(extraArgs, " ")
+	}
+	pciEnvVar := pciDeviceEnvVar(testConfig.DpdkResourceName)
+
 	m := &k8sv1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "testpmd",
+			Name:      configMapName,
 			Namespace: namespace,
 		},
 		Data: map[string]string{
-			"test.sh": `#!/usr/bin/env bash
+			"test.sh": fmt.Sprintf(`#!/usr/bin/env bash
                         export CPU=$(cat /sys/fs/cgroup/cpuset/cpuset.cpus)
+                        export PCIDEVICE=${%s}
                         echo ${CPU}
-                        echo ${PCIDEVICE_OPENSHIFT_IO_DPDKNIC}
-                        testpmd -l ${CPU} -w ${PCIDEVICE_OPENSHIFT_IO_DPDKNIC}  -- -a --portmask=0x1 --nb-cores=2 --forward-mode=mac
-                       `,
+                        echo ${PCIDEVICE}
+                        testpmd -l ${CPU} -w ${PCIDEVICE}  -- -a --portmask=0x1 --nb-cores=2 %s
+                       `, pciEnvVar, args),
 		},
 	}
 
 	By("Create testpmd wrapper script")
-	m, err := clients.K8s.CoreV1().ConfigMaps(testDpdkNamespace).Create(createTestpmdConfigMap(testDpdkNamespace))
+	m, err := clients.K8s.CoreV1().ConfigMaps(namespace).Create(m)
 	Expect(err).ToNot(HaveOccurred(), "cannot create testpmd wrapper script")
 	return m
 }
@@ -198,36 +519,213 @@ func deleteTestPod(podName string) {
 
 // witForReadiness blocks the flow until the pod phase will be "Running"
 func waitForReadiness(namespace, podName string) {
-	Eventually(func() k8sv1.PodPhase {
-		pod, err := clients.K8s.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
-		Expect(err).ToNot(HaveOccurred())
-		return pod.Status.Phase
-	}, 2*time.Minute, 1*time.Second).Should(Equal(k8sv1.PodRunning))
-
+	pods.WaitForPhase(clients.K8s, namespace, podName, k8sv1.PodRunning, 2*time.Minute)
 }
 
 // checkRxTx parses the output from the DPDK test application
 // and verifies that packets have passed the NIC TX and RX queues
-func checkRxTx(out string) {
+func checkRxTx(out string, minPackets int64) {
 	str := strings.Split(out, "\n")
 	for i := 0; i < len(str); i++ {
 		if strings.Contains(str[i], "all ports") {
 			i++
 			r := strings.Fields(str[i])
 			Expect(len(r)).To(Equal(6), "the slice doesn't contain 6 elements")
-			d, err := strconv.Atoi(r[5])
+			d, err := strconv.ParseInt(r[5], 10, 64)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(d).Should(BeNumerically(">", 0), "number of received packets should be greater then 0")
+			Expect(d).Should(BeNumerically(">=", minPackets), fmt.Sprintf("number of received packets should be at least %d", minPackets))
 
 			i++
 			r = strings.Fields(str[i])
 			Expect(len(r)).To(Equal(6), "the slice doesn't contain 6 elements")
-			d, err = strconv.Atoi(r[5])
+			d, err = strconv.ParseInt(r[5], 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d).Should(BeNumerically(">=", minPackets), fmt.Sprintf("number of transferred packets should be at least %d", minPackets))
+
+		}
+	}
+}
+
+// execInPod runs cmd inside the pod's test container and returns its stdout, failing the
+// spec and dumping the container log if the command errors out.
+func execInPod(p *k8sv1.Pod, cmd string) string {
+	stdout, stderr, err := pods.ExecCommand(clients.K8s, p, testContainerName, []string{"bash", "-c", cmd})
+	if err != nil {
+		log, logErr := pods.GetLog(clients.K8s, p, testContainerName)
+		if logErr == nil {
+			By(fmt.Sprintf("container log for pod %s:\n%s", p.Name, log))
+		}
+	}
+	Expect(err).ToNot(HaveOccurred(), fmt.Sprintf("cannot execute %q inside the pod %s: %s", cmd, p.Name, stderr))
+	return stdout
+}
+
+// runTestCmd executes the testpmd wrapper script inside p and returns its stdout,
+// dumping the container log for post-mortem diagnostics if it fails.
+func runTestCmd(p *k8sv1.Pod) string {
+	By(fmt.Sprintf("Execute %s inside the pod %s", testCmdPath, p.Name))
+	return execInPod(p, testCmdPath)
+}
+
+// runToCompletion runs cmd inside p's test container and returns its stdout. cmd is
+// expected to be wrapped in `timeout`, so a timeoutExitCode exit is the normal way the
+// run ends; any other error still fails the spec and dumps the container log.
+func runToCompletion(p *k8sv1.Pod, cmd string) string {
+	stdout, stderr, err := pods.ExecCommand(clients.K8s, p, testContainerName, []string{"bash", "-c", cmd})
+	if exitErr, ok := err.(utilexec.ExitError); ok && exitErr.ExitStatus() == timeoutExitCode {
+		return stdout
+	}
+	if err != nil {
+		log, logErr := pods.GetLog(clients.K8s, p, testContainerName)
+		if logErr == nil {
+			By(fmt.Sprintf("container log for pod %s:\n%s", p.Name, log))
+		}
+	}
+	Expect(err).ToNot(HaveOccurred(), fmt.Sprintf("cannot execute %q inside the pod %s: %s", cmd, p.Name, stderr))
+	return stdout
+}
+
+// verifyHugepageAllocation creates a smoke test pod requesting amount hugepages of
+// pageSize, then checks that the hugetlb cgroup limit equals the requested amount and
+// that /proc/meminfo inside the container reports a non-zero HugePages_Free; the free
+// count is not compared with the request.
+func verifyHugepageAllocation(pageSize, amount, cpuCount string) {
+	nodes := getListOfNodes(dpdkHostLabel)
+	n := nodes[0]
+
+	p := createSmokeTestPod(n.Name, testDpdkNamespace, dpdkAnnotationNetwork, cpuCount, pageSize, amount)
+	waitForReadiness(p.Namespace, p.Name)
+
+	cgroupSize := hugetlbCgroupSize(pageSize)
+	cmd := fmt.Sprintf(
+		"cat /sys/fs/cgroup/hugetlb/hugetlb.%s.limit_in_bytes 2>/dev/null || cat /sys/fs/cgroup/hugetlb.%s.max",
+		cgroupSize, cgroupSize,
+	)
+	out := execInPod(p, cmd)
+	limit, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	Expect(err).ToNot(HaveOccurred(), "cannot parse the hugetlb cgroup limit")
+	Expect(limit).To(Equal(resource.MustParse(amount).Value()), "cgroup hugetlb limit doesn't match the requested amount")
+
+	meminfo := execInPod(p, "cat /proc/meminfo")
+	Expect(freeHugepagesFromMeminfo(meminfo)).Should(BeNumerically(">", 0), "no free hugepages visible inside the container")
+
+	deleteTestPod(p.Name)
+}
+
+// hugetlbCgroupSize maps a hugepage size as used in pod resource requests (e.g. "1Gi",
+// "2Mi") to the unit the kernel's hugetlb cgroup controller names its files with (e.g.
+// "1GB", "2MB" in both hugetlb.<size>.limit_in_bytes and cgroup v2's hugetlb.<size>.max).
+func hugetlbCgroupSize(pageSize string) string {
+	sizes := map[string]string{
+		"1Gi": "1GB",
+		"2Mi": "2MB",
+	}
+	size, ok := sizes[pageSize]
+	Expect(ok).To(BeTrue(), fmt.Sprintf("unsupported hugepage size %q", pageSize))
+	return size
+}
+
+// freeHugepagesFromMeminfo extracts the HugePages_Free value from the contents of
+// /proc/meminfo.
+func freeHugepagesFromMeminfo(meminfo string) int64 {
+	for _, line := range strings.Split(meminfo, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "HugePages_Free") {
+			fields := strings.Fields(line)
+			v, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+			Expect(err).ToNot(HaveOccurred(), "cannot parse HugePages_Free")
+			return v
+		}
+	}
+	Fail("HugePages_Free not found in /proc/meminfo")
+	return 0
+}
+
+// execCPUSet reads the pod's assigned CPU set via its cgroup.
+func execCPUSet(p *k8sv1.Pod) string {
+	return strings.TrimSpace(execInPod(p, "cat /sys/fs/cgroup/cpuset/cpuset.cpus"))
+}
+
+// execPCIDevice reads the BDF of the SR-IOV device injected by the device plugin.
+func execPCIDevice(p *k8sv1.Pod) string {
+	return strings.TrimSpace(execInPod(p, fmt.Sprintf("echo ${%s}", pciDeviceEnvVar(testConfig.DpdkResourceName))))
+}
+
+// numaNodeOfCPUs finds the single NUMA node whose cpulist contains every CPU in
+// cpuSet (e.g. "0-3,8"), failing the spec if the CPUs span more than one node.
+func numaNodeOfCPUs(p *k8sv1.Pod, cpuSet string) int {
+	cpus := expandCPUList(cpuSet)
+	Expect(cpus).ToNot(BeEmpty(), "pod cpuset is empty")
+
+	out := execInPod(p, "for f in /sys/devices/system/node/node*/cpulist; do echo \"${f}:$(cat ${f})\"; done")
+	node := -1
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		Expect(len(parts)).To(Equal(2), "unexpected node cpulist line: "+line)
+		nodeCPUs := expandCPUList(parts[1])
+		if containsAll(nodeCPUs, cpus) {
+			node = numaNodeFromPath(parts[0])
+			break
+		}
+	}
+	Expect(node).ShouldNot(Equal(-1), "pod CPUs are not confined to a single NUMA node")
+	return node
+}
+
+// numaNodeOfPCIDevice reads the NUMA node the given PCI device (BDF) is attached to.
+func numaNodeOfPCIDevice(p *k8sv1.Pod, bdf string) int {
+	out := execInPod(p, fmt.Sprintf("cat /sys/bus/pci/devices/%s/numa_node", bdf))
+	node, err := strconv.Atoi(strings.TrimSpace(out))
+	Expect(err).ToNot(HaveOccurred(), "cannot parse the PCI device's numa_node")
+	return node
+}
+
+// numaNodeFromPath extracts the NUMA node number from a
+// /sys/devices/system/node/node<N>/cpulist path.
+func numaNodeFromPath(path string) int {
+	base := path[strings.LastIndex(path, "/node")+len("/node"):]
+	base = strings.TrimSuffix(base, "/cpulist")
+	n, err := strconv.Atoi(base)
+	Expect(err).ToNot(HaveOccurred(), "cannot parse NUMA node from path "+path)
+	return n
+}
+
+// expandCPUList expands a Linux CPU list (e.g. "0-3,8") into a sorted slice of CPU ids.
+func expandCPUList(list string) []int {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "-") {
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err := strconv.Atoi(bounds[0])
+			Expect(err).ToNot(HaveOccurred())
+			hi, err := strconv.Atoi(bounds[1])
 			Expect(err).ToNot(HaveOccurred())
-			Expect(d).Should(BeNumerically(">", 0), "number of transferred packets should be greater then 0")
+			for i := lo; i <= hi; i++ {
+				cpus = append(cpus, i)
+			}
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		Expect(err).ToNot(HaveOccurred())
+		cpus = append(cpus, v)
+	}
+	return cpus
+}
 
+// containsAll reports whether every element of subset is present in set.
+func containsAll(set, subset []int) bool {
+	present := make(map[int]bool, len(set))
+	for _, v := range set {
+		present[v] = true
+	}
+	for _, v := range subset {
+		if !present[v] {
+			return false
 		}
 	}
+	return true
 }
 
 func beforeAll(fn func()) {
@@ -0,0 +1,109 @@
+package pods
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// execTimeout bounds how long a single ExecCommand is allowed to run before its spec
+// fails instead of hanging on a wedged testpmd/ping invocation.
+const execTimeout = 5 * time.Minute
+
+// restConfig resolves the REST config used to open the exec stream. It prefers the
+// in-cluster config, since this suite normally runs as an in-cluster test pod, and falls
+// back to $KUBECONFIG or the default kubeconfig path for local runs.
+func restConfig() (*rest.Config, error) {
+	if config, err := rest.InClusterConfig(); err == nil {
+		return config, nil
+	}
+
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// ExecCommand runs cmd inside containerName of pod and returns its stdout and stderr
+// separately. It replaces shelling out to "oc rsh", so the test runner no longer needs
+// the oc binary on $PATH; outside a cluster, restConfig still reads a kubeconfig.
+func ExecCommand(client kubernetes.Interface, pod *k8sv1.Pod, containerName string, cmd []string) (string, string, error) {
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("exec").
+		VersionedParams(&k8sv1.PodExecOptions{
+			Container: containerName,
+			Command:   cmd,
+			Stdin:     false,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       false,
+		}, scheme.ParameterCodec)
+
+	config, err := restConfig()
+	if err != nil {
+		return "", "", fmt.Errorf("cannot load the REST config to exec into pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return "", "", fmt.Errorf("cannot create SPDY executor for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- executor.Stream(remotecommand.StreamOptions{
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Tty:    false,
+		})
+	}()
+
+	select {
+	case err := <-streamErr:
+		return stdout.String(), stderr.String(), err
+	case <-time.After(execTimeout):
+		return stdout.String(), stderr.String(), fmt.Errorf("timed out after %s waiting for %q inside pod %s/%s", execTimeout, cmd, pod.Namespace, pod.Name)
+	}
+}
+
+// WaitForPhase blocks until pod namespace/name reaches phase, or fails the spec once
+// timeout elapses.
+func WaitForPhase(client kubernetes.Interface, namespace, podName string, phase k8sv1.PodPhase, timeout time.Duration) {
+	Eventually(func() k8sv1.PodPhase {
+		pod, err := client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return pod.Status.Phase
+	}, timeout, 1*time.Second).Should(Equal(phase))
+}
+
+// GetLog returns containerName's log from pod, for post-mortem diagnostics when a spec
+// fails.
+func GetLog(client kubernetes.Interface, pod *k8sv1.Pod, containerName string) (string, error) {
+	req := client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &k8sv1.PodLogOptions{
+		Container: containerName,
+	})
+	out, err := req.DoRaw()
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch logs for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	return string(out), nil
+}